@@ -0,0 +1,124 @@
+package expect
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Before returns the output received before the most recent successful
+// Expect/ExpectRe/ExpectAny/ExpectCases match, equivalent to Tcl expect's
+// $expect_out(before).
+func (e *Expect) Before() string {
+	return e.before
+}
+
+// After returns the output buffered but not yet consumed by a match,
+// equivalent to Tcl expect's $expect_out(buffer).
+func (e *Expect) After() string {
+	return string(e.buf)
+}
+
+// Match returns the most recent match (n == 0, equivalent to Tcl expect's
+// $expect_out(0,string)) or its n'th regexp submatch. It returns "" if n is
+// out of range.
+func (e *Expect) Match(n int) string {
+	if n < 0 || n >= len(e.submatches) {
+		return ""
+	}
+	return e.submatches[n]
+}
+
+// DefaultMatchMax is the match_max Spawn/SpawnWithOptions apply: the most
+// bytes readUntilMatch keeps buffered while waiting for a match. See
+// SetMatchMax.
+const DefaultMatchMax = 64 * 1024
+
+// SetMatchMax overrides the match_max applied by readUntilMatch, mirroring
+// Tcl expect's `match_max -d n`. A long-running child that never emits the
+// awaited pattern, or emits lots of unrelated output first, would otherwise
+// grow e.buf without bound; once buffered output exceeds n bytes, the oldest
+// bytes are discarded.
+func (e *Expect) SetMatchMax(n int) {
+	e.matchMax = n
+}
+
+// readUntilMatch grows e.buf from the pty output, trying matchFn against the
+// unconsumed buffer after every read, until matchFn reports a match, the
+// timeout elapses, or the process exits. On a match it records Before and
+// Match/submatches and advances e.buf past the matched region.
+func (e *Expect) readUntilMatch(timeout time.Duration, matchFn func(buf []byte) (start, end int, submatches []string, ok bool)) (bool, error) {
+	if timeout == 0 {
+		// Zero timeout means expect forever: clear any deadline left over
+		// from a previous call instead of setting an immediate one.
+		e.pt.SetReadDeadline(time.Time{})
+	} else {
+		if timeout < 0 {
+			timeout = e.timeout
+		}
+		e.pt.SetReadDeadline(time.Now().Add(timeout))
+	}
+
+	chunk := make([]byte, 4096)
+	for {
+		if start, end, submatches, ok := matchFn(e.buf); ok {
+			e.before = string(e.buf[:start])
+			e.submatches = submatches
+			e.buf = e.buf[end:]
+			return true, nil
+		}
+
+		n, err := e.pt.Read(chunk)
+		if n > 0 {
+			e.buf = append(e.buf, chunk[:n]...)
+			if max := e.matchMax; max > 0 && len(e.buf) > max {
+				e.buf = e.buf[len(e.buf)-max:]
+			}
+		}
+		if err == nil {
+			continue
+		}
+		if os.IsTimeout(err) {
+			return false, err
+		}
+
+		e.Wait()
+		return false, err
+	}
+}
+
+// findPatternOrRegexp locates pattern or re (re takes priority) in buf,
+// returning the matched region and, for a regexp, its submatches (index 0
+// is the whole match).
+func findPatternOrRegexp(pattern string, re *regexp.Regexp, buf []byte) (start, end int, submatches []string, ok bool) {
+	if re != nil {
+		loc := re.FindSubmatchIndex(buf)
+		if loc == nil {
+			return 0, 0, nil, false
+		}
+		return loc[0], loc[1], submatchStrings(buf, loc), true
+	}
+	if len(pattern) > 0 {
+		idx := strings.Index(string(buf), pattern)
+		if idx < 0 {
+			return 0, 0, nil, false
+		}
+		return idx, idx + len(pattern), []string{pattern}, true
+	}
+	return 0, 0, nil, false
+}
+
+// submatchStrings converts the index pairs from FindSubmatchIndex into the
+// corresponding substrings of buf, using "" for unmatched optional groups.
+func submatchStrings(buf []byte, loc []int) []string {
+	submatches := make([]string, 0, len(loc)/2)
+	for i := 0; i < len(loc); i += 2 {
+		if loc[i] < 0 {
+			submatches = append(submatches, "")
+			continue
+		}
+		submatches = append(submatches, string(buf[loc[i]:loc[i+1]]))
+	}
+	return submatches
+}
@@ -0,0 +1,130 @@
+package expect
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindPatternOrRegexp(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		re        *regexp.Regexp
+		buf       string
+		wantStart int
+		wantEnd   int
+		wantSubs  []string
+		wantOK    bool
+	}{
+		{
+			name:      "literal pattern match",
+			pattern:   "world",
+			buf:       "hello world\n",
+			wantStart: 6,
+			wantEnd:   11,
+			wantSubs:  []string{"world"},
+			wantOK:    true,
+		},
+		{
+			name:    "literal pattern miss",
+			pattern: "bye",
+			buf:     "hello world\n",
+			wantOK:  false,
+		},
+		{
+			name:      "regexp with submatches",
+			re:        regexp.MustCompile(`user: (\w+)`),
+			buf:       "prompt\nuser: alice\n",
+			wantStart: 7,
+			wantEnd:   18,
+			wantSubs:  []string{"user: alice", "alice"},
+			wantOK:    true,
+		},
+		{
+			name:   "regexp miss",
+			re:     regexp.MustCompile(`nope`),
+			buf:    "hello world\n",
+			wantOK: false,
+		},
+		{
+			name:      "regexp takes priority over pattern",
+			re:        regexp.MustCompile(`wor\w+`),
+			pattern:   "hello",
+			buf:       "hello world\n",
+			wantStart: 6,
+			wantEnd:   11,
+			wantSubs:  []string{"world"},
+			wantOK:    true,
+		},
+		{
+			name:   "empty pattern and nil regexp never match",
+			buf:    "hello world\n",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, subs, ok := findPatternOrRegexp(tt.pattern, tt.re, []byte(tt.buf))
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("start,end = %d,%d want %d,%d", start, end, tt.wantStart, tt.wantEnd)
+			}
+			if !reflect.DeepEqual(subs, tt.wantSubs) {
+				t.Errorf("submatches = %#v, want %#v", subs, tt.wantSubs)
+			}
+		})
+	}
+}
+
+func TestSubmatchStrings(t *testing.T) {
+	re := regexp.MustCompile(`(\w+)@(\w+)?`)
+	buf := []byte("alice@")
+	loc := re.FindSubmatchIndex(buf)
+	if loc == nil {
+		t.Fatal("expected a match")
+	}
+
+	got := submatchStrings(buf, loc)
+	want := []string{"alice@", "alice", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("submatchStrings = %#v, want %#v", got, want)
+	}
+}
+
+func TestReadUntilMatchBoundsBuffer(t *testing.T) {
+	needle := "NEEDLE"
+	input := strings.Repeat("a", 50) + needle
+
+	pt, err := NewPipeThrough(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewPipeThrough: %v", err)
+	}
+	e := &Expect{pt: pt, matchMax: 10}
+
+	matched, err := e.readUntilMatch(time.Second, func(buf []byte) (int, int, []string, bool) {
+		idx := bytes.Index(buf, []byte(needle))
+		if idx < 0 {
+			return 0, 0, nil, false
+		}
+		return idx, idx + len(needle), []string{needle}, true
+	})
+	if err != nil {
+		t.Fatalf("readUntilMatch: %v", err)
+	}
+	if !matched {
+		t.Fatal("expected a match")
+	}
+	if len(e.before)+len(needle) > e.matchMax {
+		t.Errorf("buffer exceeded matchMax: before=%q (len %d), matchMax=%d", e.before, len(e.before), e.matchMax)
+	}
+}
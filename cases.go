@@ -0,0 +1,122 @@
+package expect
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrExpectTimeout is returned by ExpectCases when the timeout elapses
+// before any case matches and the caller did not supply a TimeoutCase.
+var ErrExpectTimeout = errors.New("expect: timeout waiting for pattern")
+
+// ErrExpectEOF is returned by ExpectCases when the spawned process exits
+// before any case matches and the caller did not supply an EOFCase.
+var ErrExpectEOF = errors.New("expect: process exited before pattern matched")
+
+// specialCase marks a Case as a sentinel for timeout or EOF rather than a
+// pattern to search for.
+type specialCase int
+
+const (
+	specialNone specialCase = iota
+	specialTimeout
+	specialEOF
+)
+
+// Case describes a single pattern to watch for within ExpectCases, mirroring
+// a `pattern { body }` clause in Tcl expect's `expect {}` construct.
+type Case struct {
+	// Pattern is a literal substring to search for. Ignored if Regexp is set.
+	Pattern string
+	// Regexp is a compiled pattern to search for. Takes priority over Pattern.
+	Regexp *regexp.Regexp
+	// Callback, if set, is invoked with the regexp submatches (a single
+	// element, Pattern itself, for a literal Pattern match) once this case
+	// matches. Returning an error from Callback aborts ExpectCases with that
+	// error.
+	Callback func(e *Expect, submatches []string) error
+
+	special specialCase
+}
+
+// TimeoutCase matches when ExpectCases' timeout elapses before any other
+// case matches, mirroring Tcl expect's `timeout` keyword.
+var TimeoutCase = Case{special: specialTimeout}
+
+// EOFCase matches when the spawned process exits before any other case
+// matches, mirroring Tcl expect's `eof` keyword.
+var EOFCase = Case{special: specialEOF}
+
+// ExpectCases waits for the first of several cases to match the spawned
+// process' output, mirroring Tcl expect's `expect { pat1 {body1} pat2
+// {body2} }` construct. It returns the index into cases of the matching
+// case along with any regexp submatches it captured (see also Before,
+// Match and After). Include TimeoutCase or EOFCase in cases to react to a
+// timeout or process exit explicitly; otherwise ExpectCases returns
+// ErrExpectTimeout or ErrExpectEOF.
+// Zero timeout means expect forever. Negative timeout means Default timeout.
+func (e *Expect) ExpectCases(cases []Case, timeout time.Duration) (int, []string, error) {
+	var idx int
+	matched, err := e.readUntilMatch(timeout, func(buf []byte) (int, int, []string, bool) {
+		i, start, end, submatches, ok := matchCases(cases, buf)
+		idx = i
+		return start, end, submatches, ok
+	})
+	if !matched {
+		if err != nil && os.IsTimeout(err) {
+			return e.resolveSpecialCase(cases, specialTimeout, ErrExpectTimeout)
+		}
+		return e.resolveSpecialCase(cases, specialEOF, ErrExpectEOF)
+	}
+
+	submatches := e.submatches
+	if cases[idx].Callback != nil {
+		if cbErr := cases[idx].Callback(e, submatches); cbErr != nil {
+			return idx, submatches, cbErr
+		}
+	}
+	return idx, submatches, nil
+}
+
+// matchCases evaluates every pattern/regexp case against buf in order and
+// reports the first one that matches, along with its matched region.
+func matchCases(cases []Case, buf []byte) (idx, start, end int, submatches []string, ok bool) {
+	for i, c := range cases {
+		switch {
+		case c.Regexp != nil:
+			loc := c.Regexp.FindSubmatchIndex(buf)
+			if loc == nil {
+				continue
+			}
+			return i, loc[0], loc[1], submatchStrings(buf, loc), true
+		case len(c.Pattern) > 0:
+			j := strings.Index(string(buf), c.Pattern)
+			if j < 0 {
+				continue
+			}
+			return i, j, j + len(c.Pattern), []string{c.Pattern}, true
+		}
+	}
+	return 0, 0, 0, nil, false
+}
+
+// resolveSpecialCase returns the index and callback result of the
+// TimeoutCase/EOFCase entry in cases matching kind, or -1 and fallback if
+// the caller didn't supply one.
+func (e *Expect) resolveSpecialCase(cases []Case, kind specialCase, fallback error) (int, []string, error) {
+	for i, c := range cases {
+		if c.special != kind {
+			continue
+		}
+		if c.Callback != nil {
+			if err := c.Callback(e, nil); err != nil {
+				return i, nil, err
+			}
+		}
+		return i, nil, nil
+	}
+	return -1, nil, fallback
+}
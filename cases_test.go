@@ -0,0 +1,73 @@
+package expect
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestMatchCases(t *testing.T) {
+	cases := []Case{
+		{Pattern: "nope"},
+		{Regexp: regexp.MustCompile(`user: (\w+)`)},
+		{Pattern: "world"},
+	}
+
+	idx, start, end, subs, ok := matchCases(cases, []byte("hello world\n"))
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if idx != 2 {
+		t.Errorf("idx = %d, want 2", idx)
+	}
+	if start != 6 || end != 11 {
+		t.Errorf("start,end = %d,%d want 6,11", start, end)
+	}
+	if !reflect.DeepEqual(subs, []string{"world"}) {
+		t.Errorf("submatches = %#v", subs)
+	}
+}
+
+func TestMatchCasesRegexpBeforeLaterCases(t *testing.T) {
+	cases := []Case{
+		{Pattern: "bye"},
+		{Regexp: regexp.MustCompile(`user: (\w+)`)},
+		{Pattern: "alice"},
+	}
+
+	idx, start, end, subs, ok := matchCases(cases, []byte("prompt\nuser: alice\n"))
+	if !ok {
+		t.Fatal("expected a match")
+	}
+	if idx != 1 {
+		t.Errorf("idx = %d, want 1", idx)
+	}
+	if start != 7 || end != 18 {
+		t.Errorf("start,end = %d,%d want 7,18", start, end)
+	}
+	want := []string{"user: alice", "alice"}
+	if !reflect.DeepEqual(subs, want) {
+		t.Errorf("submatches = %#v, want %#v", subs, want)
+	}
+}
+
+func TestMatchCasesNoMatch(t *testing.T) {
+	cases := []Case{
+		{Pattern: "nope"},
+		{Regexp: regexp.MustCompile(`also-nope`)},
+		TimeoutCase,
+		EOFCase,
+	}
+
+	_, _, _, _, ok := matchCases(cases, []byte("hello world\n"))
+	if ok {
+		t.Fatal("expected no match; sentinel cases must not match as patterns")
+	}
+}
+
+func TestMatchCasesEmpty(t *testing.T) {
+	idx, start, end, subs, ok := matchCases(nil, []byte("hello world\n"))
+	if ok {
+		t.Fatalf("expected no match, got idx=%d start=%d end=%d subs=%v", idx, start, end, subs)
+	}
+}
@@ -4,145 +4,107 @@
 package expect
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"os/exec"
-	"os/signal"
 	"regexp"
-	"strings"
-	"syscall"
 	"time"
 
-	"github.com/creack/pty"
 	"golang.org/x/term"
 )
 
 // DefaultTimeout is the default expect timeout.
 const DefaultTimeout = 60 * time.Second
 
+// ptyBackend abstracts the pseudo-terminal implementation so that Spawn can
+// run on Unix (via github.com/creack/pty) as well as Windows (via ConPTY).
+// See expect_unix.go and expect_windows.go for the platform-specific
+// implementations.
+type ptyBackend interface {
+	io.ReadWriteCloser
+	// Resize matches the backend's terminal size to the controlling console.
+	Resize() error
+	// Name returns the backend's pty/console name, used for diagnostics.
+	Name() string
+	// Kill forcibly terminates the attached child process.
+	Kill() error
+	// Signal sends sig to the attached child process.
+	Signal(sig os.Signal) error
+	// Pid returns the attached child process' process ID.
+	Pid() int
+	// Wait blocks until the attached child process exits.
+	Wait() error
+	// ExitCode returns the attached child process' exit code, and false if
+	// it hasn't exited yet (e.g. Wait hasn't been called or hasn't returned).
+	ExitCode() (int, bool)
+}
+
 type Expect struct {
-	// pty holds the pseudo-terminal tty
-	pty *os.File
+	// backend holds the platform pseudo-terminal the process is attached to
+	backend ptyBackend
 	// cmd contains the cmd information for the spawned process
 	cmd *exec.Cmd
 	// timeout contains the default timeout for a spawned command
 	timeout time.Duration
-	// reader is internal reader of output from spawned process
-	reader *os.File
-	// scanner scans output from reader
-	scanner *bufio.Scanner
-	// writer write to stdin
-	writer *bufio.Writer
+	// pt is the deadline-capable reader expect matches against
+	pt *PipeThrough
+	// stdout receives a copy of the child's output
+	stdout io.Writer
+	// log fans output out to stdout/log file/asciinema recorder
+	log *sessionLog
+	// buf holds output that has been read but not yet matched
+	buf []byte
+	// matchMax caps the size of buf, mirroring Tcl expect's match_max: once
+	// exceeded, the oldest bytes are discarded. See SetMatchMax.
+	matchMax int
+	// before holds the output preceding the most recent successful match
+	before string
+	// submatches holds the most recent match (index 0) and its regexp
+	// submatches (index 1+), if any
+	submatches []string
 	// oldState holds the old state of terminal
 	oldState *term.State
 	// signalCh receive certain signals from system
 	signalCh chan os.Signal
 }
 
-// Spawn starts a process.
+// Spawn starts a process, taking over the controlling terminal: os.Stdin is
+// put into raw mode and copied to the child, and the child's output is
+// copied to os.Stdout. Use SpawnWithOptions to run headless or to control
+// the child's environment, arguments or output destination.
 func Spawn(command string, timeout time.Duration) (*Expect, error) {
-	if len(command) == 0 {
-		return nil, errors.New("invalid command")
-	}
-	if timeout < 1 {
-		timeout = DefaultTimeout
-	}
-
-	commands := strings.Fields(command)
-	cmd := exec.Command(commands[0], commands[1:]...)
-
-	// Start the command with a pty
-	ptmx, err := pty.Start(cmd)
-	if err != nil {
-		return nil, err
-	}
-
-	e := &Expect{
-		pty:     ptmx,
-		cmd:     cmd,
-		timeout: timeout,
-	}
-
-	e.signalCh = make(chan os.Signal, 1)
-	signal.Notify(e.signalCh, syscall.SIGWINCH)
-	go func() {
-		for sig := range e.signalCh {
-			switch sig {
-			// handle pty size
-			case syscall.SIGWINCH:
-				if err := pty.InheritSize(os.Stdin, ptmx); err != nil {
-					log.Fatalf("error resizing pty: %s", err)
-				}
-
-			default:
-				log.Fatalf("Unknown signal: %v", sig)
-				_ = e.Wait()
-				os.Exit(-1)
-			}
-		}
-	}()
-	e.signalCh <- syscall.SIGWINCH // Initial resize
-
-	// Set stdin in raw mode
-	e.oldState, err = term.MakeRaw(int(os.Stdin.Fd()))
-	if err != nil {
-		return nil, err
-	}
-
-	var pipeWriter *os.File
-	e.reader, pipeWriter, err = os.Pipe()
-	if err != nil {
-		return nil, err
-	}
-	e.scanner = bufio.NewScanner(e.reader)
-
-	// Copy pty output to stdout and internal reader for expect
-	go func() {
-		writer := io.MultiWriter(os.Stdout, pipeWriter)
-		_, _ = io.Copy(writer, ptmx)
-		pipeWriter.Close()
-	}()
-
-	// Copy stdin to the pty
-	go func() {
-		_, _ = io.Copy(ptmx, os.Stdin)
-	}()
-
-	return e, nil
+	return SpawnWithOptions(command, Options{Interactive: true}, timeout)
 }
 
 // String implements the stringer interface.
 func (e *Expect) String() string {
 	res := fmt.Sprintf("%p: ", e)
-	if e.pty != nil {
-		res += fmt.Sprintf("pty: %s ", e.pty.Name())
+	if e.backend != nil {
+		res += fmt.Sprintf("pty: %s ", e.backend.Name())
 	}
 	if e.cmd != nil {
-		res += fmt.Sprintf("cmd: %s(%d) ", e.cmd.Path, e.cmd.Process.Pid)
+		res += fmt.Sprintf("cmd: %s(%d) ", e.cmd.Path, e.backend.Pid())
 	}
 	return res
 }
 
 // Write writes bytes b to stdin.
 func (e *Expect) Write(b []byte) (int, error) {
-	// log.Printf("console write: %q", b)
-	return e.pty.Write(b)
+	n, err := e.backend.Write(b)
+	e.log.recordInput(b[:n])
+	return n, err
 }
 
 // Send writes string s to stdin.
 func (e *Expect) Send(s string) (int, error) {
-	// log.Printf("console write: %v", s)
-	return e.pty.WriteString(s)
+	return e.Write([]byte(s))
 }
 
 // SendLine writes string s with newline to stdin.
 func (e *Expect) SendLine(s string) (int, error) {
-	// log.Printf("console write: %v", s)
-	return e.pty.WriteString(s + "\n")
+	return e.Write([]byte(s + "\n"))
 }
 
 // Expect reads spawned processes output looking for pattern.
@@ -159,46 +121,32 @@ func (e *Expect) ExpectRe(re *regexp.Regexp, timeout time.Duration) (string, err
 }
 
 // ExpectAny is similar to Expect, match string pattern or regexp re.
+//
+// On success, Before, Match and After can be used to inspect the output
+// surrounding the match.
 func (e *Expect) ExpectAny(pattern string, re *regexp.Regexp, timeout time.Duration) (string, error) {
-	if timeout < 0 {
-		timeout = e.timeout
-	}
-	e.reader.SetReadDeadline(time.Now().Add(timeout))
-
-	for e.scanner.Scan() {
-		text := e.scanner.Text()
-		if len(pattern) > 0 {
-			if strings.Contains(text, pattern) {
-				return pattern, nil
-			}
+	matched, err := e.readUntilMatch(timeout, func(buf []byte) (int, int, []string, bool) {
+		return findPatternOrRegexp(pattern, re, buf)
+	})
+	if !matched {
+		if err != nil && os.IsTimeout(err) {
+			return "", err
 		}
-		if re != nil {
-			matched := re.FindString(text)
-			if len(matched) > 0 {
-				return matched, nil
-			}
-		}
-	}
-
-	// did not found the expected output
-	e.Wait()
-
-	if e.scanner.Err() != nil {
-		return "", e.scanner.Err()
+		// cmd exit
+		return "", errors.New("command exit")
 	}
 
-	// cmd exit
-	return "", errors.New("command exit")
+	return e.submatches[0], nil
 }
 
 // Interact gives control of the child process to the interactive user (the human at the keyboard).
 func (e *Expect) Interact() error {
-	err := e.reader.Close()
+	err := e.pt.Close()
 	if err != nil {
 		return err
 	}
 
-	_, _ = io.Copy(os.Stdout, e.pty)
+	_, _ = io.Copy(e.stdout, e.backend)
 	return nil
 }
 
@@ -206,20 +154,20 @@ func (e *Expect) Interact() error {
 // Wait should be the last call to Expect.
 func (e *Expect) Wait() error {
 
-	_ = e.cmd.Wait()
+	_ = e.backend.Wait()
 
-	err := e.pty.Close()
+	err := e.backend.Close()
 	if err != nil {
 		return err
 	}
 
-	signal.Stop(e.signalCh)
-	close(e.signalCh)
+	stopResize(e)
 
-	// restore terminal state before
-	err = term.Restore(int(os.Stdin.Fd()), e.oldState)
-	if err != nil {
-		return err
+	// restore terminal state before, if Spawn put it into raw mode
+	if e.oldState != nil {
+		if err := term.Restore(int(os.Stdin.Fd()), e.oldState); err != nil {
+			return err
+		}
 	}
 
 	return nil
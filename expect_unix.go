@@ -0,0 +1,113 @@
+//go:build !windows
+
+package expect
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"github.com/creack/pty"
+)
+
+// Winsize describes a pseudo-terminal size, mirroring github.com/creack/pty.Winsize.
+type Winsize = pty.Winsize
+
+// unixPty adapts github.com/creack/pty's pseudo-terminal handle to the
+// ptyBackend interface.
+type unixPty struct {
+	f   *os.File
+	pid int
+	cmd *exec.Cmd
+}
+
+func (u *unixPty) Read(p []byte) (int, error)  { return u.f.Read(p) }
+func (u *unixPty) Write(p []byte) (int, error) { return u.f.Write(p) }
+func (u *unixPty) Close() error                { return u.f.Close() }
+func (u *unixPty) Name() string                { return u.f.Name() }
+func (u *unixPty) Pid() int                    { return u.pid }
+
+// Wait blocks until cmd exits.
+func (u *unixPty) Wait() error {
+	return u.cmd.Wait()
+}
+
+// ExitCode reports cmd's exit code, once Wait has returned.
+func (u *unixPty) ExitCode() (int, bool) {
+	if u.cmd.ProcessState == nil {
+		return 0, false
+	}
+	return u.cmd.ProcessState.ExitCode(), true
+}
+
+// Resize inherits the size of the controlling terminal (os.Stdin).
+func (u *unixPty) Resize() error {
+	return pty.InheritSize(os.Stdin, u.f)
+}
+
+// Kill sends SIGKILL to the child's whole process group.
+func (u *unixPty) Kill() error {
+	return syscall.Kill(-u.pid, syscall.SIGKILL)
+}
+
+// Signal sends sig to the child's whole process group.
+func (u *unixPty) Signal(sig os.Signal) error {
+	s, ok := sig.(syscall.Signal)
+	if !ok {
+		return fmt.Errorf("expect: unsupported signal %v", sig)
+	}
+	return syscall.Kill(-u.pid, s)
+}
+
+// startPty spawns cmd attached to a new pseudo-terminal, sized to size if
+// given or the backend's default size otherwise. pty.Start/StartWithSize run
+// cmd in a new session, which also makes it the leader of its own process
+// group, so Kill/SendSignal can reach the whole job, not just the immediate
+// child.
+func startPty(cmd *exec.Cmd, size *Winsize) (ptyBackend, error) {
+	var (
+		f   *os.File
+		err error
+	)
+	if size != nil {
+		f, err = pty.StartWithSize(cmd, size)
+	} else {
+		f, err = pty.Start(cmd)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &unixPty{f: f, pid: cmd.Process.Pid, cmd: cmd}, nil
+}
+
+// watchResize forwards SIGWINCH to the pty so the child sees terminal
+// resize events.
+func watchResize(e *Expect) {
+	signal.Notify(e.signalCh, syscall.SIGWINCH)
+	go func() {
+		for sig := range e.signalCh {
+			switch sig {
+			// handle pty size
+			case syscall.SIGWINCH:
+				if err := e.backend.Resize(); err != nil {
+					log.Fatalf("error resizing pty: %s", err)
+				}
+
+			default:
+				log.Fatalf("Unknown signal: %v", sig)
+				_ = e.Wait()
+				os.Exit(-1)
+			}
+		}
+	}()
+	e.signalCh <- syscall.SIGWINCH // Initial resize
+}
+
+// stopResize stops forwarding resize signals and releases signalCh.
+func stopResize(e *Expect) {
+	signal.Stop(e.signalCh)
+	close(e.signalCh)
+}
@@ -0,0 +1,142 @@
+//go:build windows
+
+package expect
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/UserExistsError/conpty"
+	"golang.org/x/term"
+)
+
+// Winsize describes a pseudo-terminal size.
+type Winsize struct {
+	Rows, Cols, X, Y uint16
+}
+
+// windowsPty adapts a ConPTY pseudo console to the ptyBackend interface.
+type windowsPty struct {
+	cpty *conpty.ConPty
+
+	mu       sync.Mutex
+	exitCode int
+	exited   bool
+}
+
+func (w *windowsPty) Read(p []byte) (int, error)  { return w.cpty.Read(p) }
+func (w *windowsPty) Write(p []byte) (int, error) { return w.cpty.Write(p) }
+func (w *windowsPty) Close() error                { return w.cpty.Close() }
+func (w *windowsPty) Name() string                { return "conpty" }
+func (w *windowsPty) Pid() int                    { return w.cpty.Pid() }
+
+// Wait blocks until the process attached to the pseudo console exits.
+// Unlike Unix, where cmd is Start()ed directly and cmd.Wait() applies,
+// ConPTY spawns the process itself, so we wait on the ConPty handle instead.
+func (w *windowsPty) Wait() error {
+	code, err := w.cpty.Wait(context.Background())
+	w.mu.Lock()
+	w.exitCode = int(code)
+	w.exited = true
+	w.mu.Unlock()
+	return err
+}
+
+// ExitCode reports the process' exit code, once Wait has returned.
+func (w *windowsPty) ExitCode() (int, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.exitCode, w.exited
+}
+
+// Resize matches the pseudo console size to the current console window.
+func (w *windowsPty) Resize() error {
+	cols, rows, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return err
+	}
+	return w.cpty.Resize(cols, rows)
+}
+
+// Kill terminates the pseudo console and the process attached to it.
+func (w *windowsPty) Kill() error {
+	return w.cpty.Close()
+}
+
+// Signal sends sig to the attached process. Windows only supports
+// os.Kill through os.Process.Signal; anything else returns an error.
+func (w *windowsPty) Signal(sig os.Signal) error {
+	proc, err := os.FindProcess(w.cpty.Pid())
+	if err != nil {
+		return err
+	}
+	return proc.Signal(sig)
+}
+
+// startPty spawns cmd attached to a new ConPTY pseudo console, sized to
+// size if given or the backend's default size otherwise, with cmd's Env and
+// Dir (set from Options.Env/Options.Dir) passed through to the console.
+func startPty(cmd *exec.Cmd, size *Winsize) (ptyBackend, error) {
+	opts := []conpty.ConPtyOption{}
+	if size != nil {
+		opts = append(opts, conpty.ConPtyDimensions(int(size.Cols), int(size.Rows)))
+	}
+	if cmd.Dir != "" {
+		opts = append(opts, conpty.ConPtyWorkDir(cmd.Dir))
+	}
+	if cmd.Env != nil {
+		opts = append(opts, conpty.ConPtyEnv(cmd.Env))
+	}
+	cpty, err := conpty.Start(commandLine(cmd), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &windowsPty{cpty: cpty}, nil
+}
+
+// commandLine quotes cmd's path and arguments into a single Windows command
+// line, the form ConPTY expects. Unlike cmd.String() (documented as for
+// debugging only), this escapes each argument so values containing spaces
+// or quotes round-trip correctly.
+func commandLine(cmd *exec.Cmd) string {
+	parts := make([]string, 0, len(cmd.Args))
+	parts = append(parts, syscall.EscapeArg(cmd.Path))
+	for _, arg := range cmd.Args[1:] {
+		parts = append(parts, syscall.EscapeArg(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// watchResize has no SIGWINCH equivalent on Windows, so it polls the
+// console window size and forwards changes to the pseudo console.
+func watchResize(e *Expect) {
+	go func() {
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+
+		var lastCols, lastRows int
+		for {
+			select {
+			case <-e.signalCh:
+				return
+			case <-ticker.C:
+				cols, rows, err := term.GetSize(int(os.Stdout.Fd()))
+				if err != nil || (cols == lastCols && rows == lastRows) {
+					continue
+				}
+				lastCols, lastRows = cols, rows
+				_ = e.backend.Resize()
+			}
+		}
+	}()
+}
+
+// stopResize stops the console size polling goroutine.
+func stopResize(e *Expect) {
+	close(e.signalCh)
+}
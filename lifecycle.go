@@ -0,0 +1,58 @@
+package expect
+
+import (
+	"fmt"
+	"os"
+)
+
+// Kill forcibly terminates the spawned process, for hung or unresponsive
+// children that Wait alone can't clean up.
+func (e *Expect) Kill() error {
+	return e.backend.Kill()
+}
+
+// SendSignal sends sig to the spawned process.
+func (e *Expect) SendSignal(sig os.Signal) error {
+	return e.backend.Signal(sig)
+}
+
+// SendControl sends a control character through the pty's line discipline,
+// e.g. e.SendControl('c') for Ctrl-C or e.SendControl('d') for Ctrl-D.
+// Ctrl-@ (NUL) is not supported.
+func (e *Expect) SendControl(r rune) error {
+	b, err := controlByte(r)
+	if err != nil {
+		return err
+	}
+	_, err = e.Write([]byte{b})
+	return err
+}
+
+// controlByte maps r to the byte a terminal sends for Ctrl-<r>.
+func controlByte(r rune) (byte, error) {
+	switch r {
+	case '[': // Ctrl-[, ESC
+		return 0x1b, nil
+	case '\\': // Ctrl-\, FS
+		return 0x1c, nil
+	case ']': // Ctrl-], GS
+		return 0x1d, nil
+	case '^': // Ctrl-^, RS
+		return 0x1e, nil
+	case '_': // Ctrl-_, US
+		return 0x1f, nil
+	}
+	switch {
+	case r >= 'a' && r <= 'z':
+		return byte(r-'a') + 1, nil
+	case r >= 'A' && r <= 'Z':
+		return byte(r-'A') + 1, nil
+	}
+	return 0, fmt.Errorf("expect: unsupported control character %q", r)
+}
+
+// ExitStatus returns the spawned process' exit code, and false if it hasn't
+// been reaped yet (e.g. by Wait).
+func (e *Expect) ExitStatus() (int, bool) {
+	return e.backend.ExitCode()
+}
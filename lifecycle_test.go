@@ -0,0 +1,41 @@
+package expect
+
+import "testing"
+
+func TestControlByte(t *testing.T) {
+	tests := []struct {
+		r    rune
+		want byte
+	}{
+		{'c', 0x03}, // Ctrl-C, ETX
+		{'d', 0x04}, // Ctrl-D, EOT
+		{'z', 0x1a}, // Ctrl-Z, SUB
+		{'a', 0x01},
+		{'A', 0x01},
+		{'Z', 0x1a},
+		{'[', 0x1b}, // ESC
+		{'\\', 0x1c},
+		{']', 0x1d},
+		{'^', 0x1e},
+		{'_', 0x1f},
+	}
+
+	for _, tt := range tests {
+		got, err := controlByte(tt.r)
+		if err != nil {
+			t.Errorf("controlByte(%q) returned error: %v", tt.r, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("controlByte(%q) = %#x, want %#x", tt.r, got, tt.want)
+		}
+	}
+}
+
+func TestControlByteUnsupported(t *testing.T) {
+	for _, r := range []rune{'1', ' ', '!', '@'} {
+		if _, err := controlByte(r); err == nil {
+			t.Errorf("controlByte(%q) expected an error, got nil", r)
+		}
+	}
+}
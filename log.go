@@ -0,0 +1,178 @@
+package expect
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// sessionLog fans out the spawned process' output to the terminal, an
+// optional user-supplied log writer, and an optional asciinema recorder,
+// mirroring Tcl expect's log_user/log_file. It also forwards input sent via
+// Write/Send/SendLine to the asciinema recorder.
+type sessionLog struct {
+	mu        sync.Mutex
+	logUser   bool
+	stdout    io.Writer
+	logFile   io.Writer
+	asciinema *asciinemaEncoder
+}
+
+// newSessionLog returns a sessionLog that mirrors output to stdout until
+// told otherwise.
+func newSessionLog(stdout io.Writer) *sessionLog {
+	return &sessionLog{logUser: true, stdout: stdout}
+}
+
+// Write implements io.Writer so sessionLog can be used directly as an
+// io.MultiWriter target for the pty output copy goroutine.
+func (l *sessionLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.logUser && l.stdout != nil {
+		if _, err := l.stdout.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	if l.logFile != nil {
+		if _, err := l.logFile.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	if l.asciinema != nil {
+		if err := l.asciinema.writeOutput(p); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// recordInput forwards input sent to the child to the asciinema recorder,
+// if one is attached.
+func (l *sessionLog) recordInput(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.asciinema != nil {
+		_ = l.asciinema.writeInput(p)
+	}
+}
+
+func (l *sessionLog) setLogFile(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logFile = w
+}
+
+func (l *sessionLog) setLogUser(on bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logUser = on
+}
+
+func (l *sessionLog) setAsciinema(enc *asciinemaEncoder) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.asciinema = enc
+}
+
+// LogFile mirrors all output from the spawned process to w, equivalent to
+// Tcl expect's log_file. Pass nil to stop logging to a file.
+func (e *Expect) LogFile(w io.Writer) {
+	e.log.setLogFile(w)
+}
+
+// LogUser controls whether output is also copied to Stdout (os.Stdout by
+// default, or whatever Options.Stdout was given to SpawnWithOptions),
+// equivalent to Tcl expect's log_user. It defaults to true.
+func (e *Expect) LogUser(on bool) {
+	e.log.setLogUser(on)
+}
+
+// RecordAsciinema records the session as an asciinema v2 cast to w, which
+// can be replayed directly with `asciinema play`. width and height describe
+// the terminal size recorded in the cast header.
+func (e *Expect) RecordAsciinema(w io.Writer, width, height int) error {
+	enc, err := newAsciinemaEncoder(w, width, height, e.cmd.Env)
+	if err != nil {
+		return err
+	}
+	e.log.setAsciinema(enc)
+	return nil
+}
+
+// asciinemaEncoder writes an asciinema v2 cast file: a header record
+// followed by one [elapsed, kind, data] event per read/write.
+type asciinemaEncoder struct {
+	mu    sync.Mutex
+	w     io.Writer
+	start time.Time
+}
+
+func newAsciinemaEncoder(w io.Writer, width, height int, env []string) (*asciinemaEncoder, error) {
+	header := struct {
+		Version   int               `json:"version"`
+		Width     int               `json:"width"`
+		Height    int               `json:"height"`
+		Timestamp int64             `json:"timestamp"`
+		Env       map[string]string `json:"env,omitempty"`
+	}{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: time.Now().Unix(),
+		Env:       envToMap(env),
+	}
+
+	data, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		return nil, err
+	}
+
+	return &asciinemaEncoder{w: w, start: time.Now()}, nil
+}
+
+func (a *asciinemaEncoder) writeOutput(p []byte) error { return a.writeEvent("o", p) }
+
+func (a *asciinemaEncoder) writeInput(p []byte) error { return a.writeEvent("i", p) }
+
+func (a *asciinemaEncoder) writeEvent(kind string, p []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	event := []interface{}{time.Since(a.start).Seconds(), kind, string(p)}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = a.w.Write(append(data, '\n'))
+	return err
+}
+
+// envToMap converts a Cmd.Env-style []string of "KEY=VALUE" pairs into a map
+// for the asciinema header. Entries without "=" are skipped.
+func envToMap(env []string) map[string]string {
+	if len(env) == 0 {
+		return nil
+	}
+
+	m := make(map[string]string, len(env))
+	for _, kv := range env {
+		for i := 0; i < len(kv); i++ {
+			if kv[i] == '=' {
+				m[kv[:i]] = kv[i+1:]
+				break
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,92 @@
+package expect
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEnvToMap(t *testing.T) {
+	got := envToMap([]string{"FOO=bar", "EMPTY=", "NOEQUALS", "A=B=C"})
+	want := map[string]string{"FOO": "bar", "EMPTY": "", "A": "B=C"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("envToMap = %#v, want %#v", got, want)
+	}
+
+	if got := envToMap(nil); got != nil {
+		t.Errorf("envToMap(nil) = %#v, want nil", got)
+	}
+}
+
+func TestAsciinemaEncoderHeaderAndEvents(t *testing.T) {
+	var buf bytes.Buffer
+	enc, err := newAsciinemaEncoder(&buf, 80, 24, []string{"TERM=xterm"})
+	if err != nil {
+		t.Fatalf("newAsciinemaEncoder: %v", err)
+	}
+	if err := enc.writeOutput([]byte("hello\n")); err != nil {
+		t.Fatalf("writeOutput: %v", err)
+	}
+	if err := enc.writeInput([]byte("ls\n")); err != nil {
+		t.Fatalf("writeInput: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimRight(buf.Bytes(), "\n"), []byte("\n"))
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3 (header + 2 events): %q", len(lines), buf.String())
+	}
+
+	var header struct {
+		Version int               `json:"version"`
+		Width   int               `json:"width"`
+		Height  int               `json:"height"`
+		Env     map[string]string `json:"env"`
+	}
+	if err := json.Unmarshal(lines[0], &header); err != nil {
+		t.Fatalf("unmarshal header: %v", err)
+	}
+	if header.Version != 2 || header.Width != 80 || header.Height != 24 {
+		t.Errorf("header = %+v, want version=2 width=80 height=24", header)
+	}
+	if header.Env["TERM"] != "xterm" {
+		t.Errorf("header.Env = %#v, want TERM=xterm", header.Env)
+	}
+
+	var outEvent []interface{}
+	if err := json.Unmarshal(lines[1], &outEvent); err != nil {
+		t.Fatalf("unmarshal output event: %v", err)
+	}
+	if outEvent[1] != "o" || outEvent[2] != "hello\n" {
+		t.Errorf("output event = %#v, want [elapsed, \"o\", \"hello\\n\"]", outEvent)
+	}
+
+	var inEvent []interface{}
+	if err := json.Unmarshal(lines[2], &inEvent); err != nil {
+		t.Fatalf("unmarshal input event: %v", err)
+	}
+	if inEvent[1] != "i" || inEvent[2] != "ls\n" {
+		t.Errorf("input event = %#v, want [elapsed, \"i\", \"ls\\n\"]", inEvent)
+	}
+}
+
+func TestSessionLogRespectsLogUserAndLogFile(t *testing.T) {
+	var stdout, logFile bytes.Buffer
+	log := newSessionLog(&stdout)
+	log.setLogFile(&logFile)
+
+	if _, err := log.Write([]byte("a")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	log.setLogUser(false)
+	if _, err := log.Write([]byte("b")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if stdout.String() != "a" {
+		t.Errorf("stdout = %q, want %q (LogUser(false) should stop terminal echo)", stdout.String(), "a")
+	}
+	if logFile.String() != "ab" {
+		t.Errorf("logFile = %q, want %q (log file should always receive output)", logFile.String(), "ab")
+	}
+}
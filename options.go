@@ -0,0 +1,126 @@
+package expect
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Options configures SpawnWithOptions.
+type Options struct {
+	// Interactive controls whether the spawned process takes over the
+	// controlling terminal: os.Stdin is put into raw mode and copied to the
+	// child. Set to false to run headless, e.g. from CI, tests or daemons
+	// where os.Stdin isn't a tty and term.MakeRaw would fail with ENOTTY.
+	Interactive bool
+	// Env, if non-nil, sets the spawned process' environment. See exec.Cmd.Env.
+	Env []string
+	// Dir sets the spawned process' working directory. See exec.Cmd.Dir.
+	Dir string
+	// Args, if set, are passed to command as separate arguments instead of
+	// splitting command on whitespace, so values containing spaces or
+	// quotes don't need shell-style escaping.
+	Args []string
+	// PtySize sets the initial pseudo-terminal size. A nil PtySize inherits
+	// the backend's default size. When Stdout is a terminal, the pty is then
+	// resized to follow the controlling terminal, replacing PtySize.
+	PtySize *Winsize
+	// Stdout receives a copy of the child's output. Defaults to os.Stdout;
+	// use io.Discard to run silently.
+	Stdout io.Writer
+}
+
+// SpawnWithOptions starts a process with control over its environment,
+// arguments, pty size, output destination and whether the controlling
+// terminal is taken over, unlike Spawn which always runs interactively.
+func SpawnWithOptions(command string, opts Options, timeout time.Duration) (*Expect, error) {
+	if len(command) == 0 {
+		return nil, errors.New("invalid command")
+	}
+	if timeout < 1 {
+		timeout = DefaultTimeout
+	}
+
+	name, args := command, opts.Args
+	if args == nil {
+		fields := strings.Fields(command)
+		name, args = fields[0], fields[1:]
+	}
+	cmd := exec.Command(name, args...)
+	if opts.Env != nil {
+		cmd.Env = opts.Env
+	}
+	if opts.Dir != "" {
+		cmd.Dir = opts.Dir
+	}
+
+	backend, err := startPty(cmd, opts.PtySize)
+	if err != nil {
+		return nil, err
+	}
+
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	e := &Expect{
+		backend:  backend,
+		cmd:      cmd,
+		timeout:  timeout,
+		stdout:   stdout,
+		log:      newSessionLog(stdout),
+		matchMax: DefaultMatchMax,
+	}
+
+	e.signalCh = make(chan os.Signal, 1)
+	if isTerminal(stdout) {
+		watchResize(e)
+	}
+
+	if opts.Interactive {
+		// Set stdin in raw mode
+		e.oldState, err = term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	e.pt, err = NewPipeThrough(pipeReader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy pty output to stdout/log file/asciinema recorder and internal
+	// reader for expect
+	go func() {
+		writer := io.MultiWriter(e.log, pipeWriter)
+		_, _ = io.Copy(writer, e.backend)
+		pipeWriter.Close()
+	}()
+
+	// Copy stdin to the pty
+	if opts.Interactive {
+		go func() {
+			_, _ = io.Copy(e.backend, os.Stdin)
+		}()
+	}
+
+	return e, nil
+}
+
+// isTerminal reports whether w is a terminal, used to decide whether to
+// watch for resize events.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd()))
+}